@@ -0,0 +1,135 @@
+package framing
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+)
+
+func TestDelimitedReader_ShortRead(t *testing.T) {
+	// Declares a 10 byte message but the stream is cut off after 3, mimicking
+	// a peer that closes the connection mid-frame.
+	var buf bytes.Buffer
+	buf.Write(proto.EncodeVarint(10))
+	buf.Write([]byte{1, 2, 3})
+
+	_, _, err := NewDelimitedReader(&buf, 1024).Next(nil)
+	if err == nil {
+		t.Fatal("expected an error on a short read, got nil")
+	}
+	if err != io.ErrUnexpectedEOF && err != io.EOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF or io.EOF, got %v", err)
+	}
+}
+
+func TestDelimitedReader_OversizedVarint(t *testing.T) {
+	// Declares a message far larger than maxSize; Next must reject it before
+	// attempting to read or allocate that many bytes.
+	var buf bytes.Buffer
+	buf.Write(proto.EncodeVarint(1 << 40))
+	buf.Write(bytes.Repeat([]byte{0xff}, 16))
+
+	_, _, err := NewDelimitedReader(&buf, 1024).Next(nil)
+	if err == nil {
+		t.Fatal("expected an error for a length prefix exceeding maxSize, got nil")
+	}
+}
+
+func TestDelimitedReader_SnappyBomb(t *testing.T) {
+	// A highly compressible payload whose decoded size vastly exceeds
+	// maxSize. The declared (uncompressed) length in the varint prefix is
+	// honest, so Next should reject it against maxSize before ever touching
+	// the snappy decoder.
+	const maxSize = 1024
+	bomb := bytes.Repeat([]byte{0}, 10*1024*1024)
+	compressed := snappy.Encode(nil, bomb)
+
+	var buf bytes.Buffer
+	buf.Write(proto.EncodeVarint(uint64(len(bomb))))
+	buf.Write(compressed)
+
+	decompress := func(r io.Reader) (io.Reader, error) {
+		return snappy.NewReader(r), nil
+	}
+	_, _, err := NewDelimitedReader(&buf, maxSize).Next(decompress)
+	if err == nil {
+		t.Fatal("expected an error for a decoded size exceeding maxSize, got nil")
+	}
+}
+
+func TestDelimitedReader_RoundTrip(t *testing.T) {
+	want := []byte("some ssz encoded payload")
+	var buf bytes.Buffer
+	if _, err := NewDelimitedWriter(&buf).WriteMsg(want, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, release, err := NewDelimitedReader(&buf, 1024).Next(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDelimitedReader_DoesNotOverreadIntoNextFrame(t *testing.T) {
+	// Two frames back to back on one stream. Reading the first must not
+	// consume any bytes belonging to the second.
+	first := []byte("first message")
+	second := []byte("second message")
+
+	var buf bytes.Buffer
+	w := NewDelimitedWriter(&buf)
+	if _, err := w.WriteMsg(first, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteMsg(second, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewDelimitedReader(&buf, 1024)
+	got1, release1, err := r.Next(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got1, first) {
+		t.Fatalf("first message: got %q, want %q", got1, first)
+	}
+	release1()
+
+	got2, release2, err := r.Next(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release2()
+	if !bytes.Equal(got2, second) {
+		t.Fatalf("second message: got %q, want %q", got2, second)
+	}
+}
+
+// FuzzDelimitedReader exercises Next against arbitrary byte streams, the
+// attacker-controlled input in a real libp2p connection, to make sure
+// malformed varints or truncated frames only ever produce an error and never
+// panic or hang.
+func FuzzDelimitedReader(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x80})
+	f.Add(append(proto.EncodeVarint(5), []byte("hi")...))
+	f.Add(proto.EncodeVarint(1 << 40))
+
+	// maxFuzzSize bounds the reader so a pathological but otherwise "valid"
+	// varint doesn't attempt a multi-gigabyte allocation.
+	const maxFuzzSize = 1 << 20
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, release, err := NewDelimitedReader(bytes.NewReader(data), maxFuzzSize).Next(nil)
+		if err == nil {
+			release()
+		}
+	})
+}
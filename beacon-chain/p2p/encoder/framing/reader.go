@@ -0,0 +1,91 @@
+// Package framing implements length-delimited message framing for the p2p
+// req/resp wire format, in the spirit of Tendermint's libs/protoio. It exists
+// because a naive buffered reader can silently discard bytes belonging to the
+// next message when several chunks arrive back-to-back on one libp2p stream;
+// DelimitedReader guarantees callers can never read past the message they
+// asked for.
+package framing
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// bufPool recycles the byte slices used to hold one decoded message, so a
+// fresh maxSize-sized allocation isn't made on every call.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+// Decompressor wraps an underlying frame reader with a decompression codec
+// (snappy, zstd, ...). It is supplied by the caller so this package stays
+// agnostic of any particular compression scheme.
+type Decompressor func(r io.Reader) (io.Reader, error)
+
+// DelimitedReader reads length-prefixed messages off of an underlying
+// stream, one at a time. Next returns the exact decoded payload for a single
+// message. Without a Decompressor it never over-reads into the frame that
+// follows it; with one, it consumes whatever the decompressor reads from the
+// stream.
+type DelimitedReader interface {
+	// Next parses the next varint length prefix, optionally decompresses the
+	// frame via decompress, and returns exactly msgLen decoded bytes. maxSize
+	// is enforced against the decoded length. The returned release func must
+	// be called once the caller is done with the returned slice.
+	Next(decompress Decompressor) (b []byte, release func(), err error)
+}
+
+type delimitedReader struct {
+	r       io.Reader
+	maxSize uint64
+}
+
+// NewDelimitedReader returns a DelimitedReader that enforces maxSize against
+// the decoded payload length of every frame it parses from r.
+func NewDelimitedReader(r io.Reader, maxSize uint64) DelimitedReader {
+	return &delimitedReader{r: r, maxSize: maxSize}
+}
+
+// Next implements DelimitedReader.
+func (d *delimitedReader) Next(decompress Decompressor) ([]byte, func(), error) {
+	msgLen, err := readVarint(d.r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if msgLen > d.maxSize {
+		return nil, nil, fmt.Errorf("size of decoded message is %d which is larger than the provided max limit of %d", msgLen, d.maxSize)
+	}
+
+	frame := d.r
+	if decompress != nil {
+		frame, err = decompress(frame)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	bufPtr := bufPool.Get().(*[]byte)
+	buf := grow(*bufPtr, int(msgLen))
+	if _, err := io.ReadFull(frame, buf); err != nil {
+		bufPool.Put(bufPtr)
+		return nil, nil, err
+	}
+	release := func() {
+		*bufPtr = buf[:0]
+		bufPool.Put(bufPtr)
+	}
+	return buf, release, nil
+}
+
+// grow returns a slice of buf with length n, reusing buf's backing array
+// when it is already large enough.
+func grow(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,39 @@
+package framing
+
+import (
+	"errors"
+	"io"
+)
+
+// maxVarintBytes is the maximum number of bytes a protobuf varint can occupy.
+// A uint64 varint never needs more than 10 bytes; anything longer is malformed
+// or an attempt to exhaust the reader.
+const maxVarintBytes = 10
+
+// errInvalidVarint is returned when a length prefix cannot be parsed as a
+// valid protobuf varint within maxVarintBytes.
+var errInvalidVarint = errors.New("invalid varint length prefix")
+
+// readVarint reads a single protobuf varint off of r, one byte at a time, so
+// it never reads past the end of the length prefix into the payload that
+// follows it on the same stream.
+func readVarint(r io.Reader) (uint64, error) {
+	var buf [maxVarintBytes]byte
+	var x uint64
+	var s uint
+	for i := 0; i < maxVarintBytes; i++ {
+		if _, err := io.ReadFull(r, buf[i:i+1]); err != nil {
+			return 0, err
+		}
+		b := buf[i]
+		if b < 0x80 {
+			if i == maxVarintBytes-1 && b > 1 {
+				return 0, errInvalidVarint
+			}
+			return x | uint64(b)<<s, nil
+		}
+		x |= uint64(b&0x7f) << s
+		s += 7
+	}
+	return 0, errInvalidVarint
+}
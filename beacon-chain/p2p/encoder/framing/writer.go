@@ -0,0 +1,54 @@
+package framing
+
+import (
+	"io"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Compressor wraps an underlying frame writer with a compression codec
+// (snappy, zstd, ...). It is supplied by the caller so this package stays
+// agnostic of any particular compression scheme.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// DelimitedWriter writes length-prefixed messages to an underlying stream,
+// one at a time.
+type DelimitedWriter interface {
+	// WriteMsg writes b as a single varint-length-prefixed frame, optionally
+	// compressing it via compress. The varint always carries the
+	// uncompressed length of b.
+	WriteMsg(b []byte, compress Compressor) (int, error)
+}
+
+type delimitedWriter struct {
+	w io.Writer
+}
+
+// NewDelimitedWriter returns a DelimitedWriter that writes frames to w.
+func NewDelimitedWriter(w io.Writer) DelimitedWriter {
+	return &delimitedWriter{w: w}
+}
+
+// WriteMsg implements DelimitedWriter.
+func (d *delimitedWriter) WriteMsg(b []byte, compress Compressor) (int, error) {
+	prefix := proto.EncodeVarint(uint64(len(b)))
+	n, err := d.w.Write(prefix)
+	if err != nil {
+		return n, err
+	}
+
+	if compress == nil {
+		m, err := d.w.Write(b)
+		return n + m, err
+	}
+
+	cw, err := compress(d.w)
+	if err != nil {
+		return n, err
+	}
+	m, werr := cw.Write(b)
+	if cerr := cw.Close(); werr == nil {
+		werr = cerr
+	}
+	return n + m, werr
+}
@@ -0,0 +1,106 @@
+package encoder
+
+import (
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// errCodecNotRegistered is returned when a (protocolID, message type) pair
+// has no registered Codec.
+var errCodecNotRegistered = errors.New("no codec registered for protocol and message type")
+
+// SchemaDescriptor is implemented by message types that can report their own
+// worst-case SSZ encoded size. RegisterCodec requires it so a registered
+// Codec's MaxSize always reflects the type's actual SSZ bounds, rather than
+// trusting a caller-supplied number that can silently drift from the schema
+// (e.g. a SignedBeaconBlock's ~1Mb bound vs. a Status message's few hundred
+// bytes).
+type SchemaDescriptor interface {
+	// SSZMaxSize returns the maximum number of bytes this message's SSZ
+	// encoding may occupy.
+	SSZMaxSize() uint64
+}
+
+// Codec bundles everything the p2p req/resp dispatch needs to read or write
+// one message type over one protocol: the wire encoding, the message's SSZ
+// schema descriptor, and the MaxSize computed from it.
+type Codec struct {
+	// Encoding is the wire encoder used to (de)serialize messages of this
+	// type, e.g. SszNetworkEncoder or SszZstdNetworkEncoder.
+	Encoding NetworkEncoding
+	// Schema is the registered prototype's SchemaDescriptor, kept alongside
+	// MaxSize so callers can inspect the type's SSZ bounds directly.
+	Schema SchemaDescriptor
+	// MaxSize is the maximum number of bytes a decoded message of this type
+	// may occupy, taken from Schema.SSZMaxSize() at registration time.
+	MaxSize uint64
+}
+
+// registryKey identifies one (protocol, message type) pair within a
+// Registry.
+type registryKey struct {
+	protocolID string
+	msgType    reflect.Type
+}
+
+// Registry maps a (protocolID, message type) pair to the Codec that should
+// be used to encode or decode it, so callers no longer need to thread a
+// maxSize through every DecodeWithMaxLength call site.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[registryKey]Codec
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{codecs: make(map[registryKey]Codec)}
+}
+
+// RegisterCodec associates protocolID and the concrete type of prototype
+// with encoding. prototype's SSZMaxSize becomes the registered Codec's
+// MaxSize. It is typically called once per message type during p2p service
+// setup.
+func (r *Registry) RegisterCodec(protocolID string, prototype SchemaDescriptor, encoding NetworkEncoding) {
+	key := registryKey{protocolID: protocolID, msgType: reflect.TypeOf(prototype)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[key] = Codec{Encoding: encoding, Schema: prototype, MaxSize: prototype.SSZMaxSize()}
+}
+
+// Codec returns the registered Codec for protocolID and the concrete type of
+// msg, or errCodecNotRegistered if none was registered.
+func (r *Registry) Codec(protocolID string, msg interface{}) (Codec, error) {
+	key := registryKey{protocolID: protocolID, msgType: reflect.TypeOf(msg)}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codec, ok := r.codecs[key]
+	if !ok {
+		return Codec{}, errors.Wrapf(errCodecNotRegistered, "protocol %q message type %s", protocolID, key.msgType)
+	}
+	return codec, nil
+}
+
+// EncodeRegistered writes msg to w using the Codec registered for protocolID
+// and msg's concrete type, so the req/resp dispatch doesn't need to pass
+// maxSize at the call site.
+func (r *Registry) EncodeRegistered(w io.Writer, protocolID string, msg interface{}) (int, error) {
+	codec, err := r.Codec(protocolID, msg)
+	if err != nil {
+		return 0, err
+	}
+	return codec.Encoding.EncodeWithMaxLength(w, msg, codec.MaxSize)
+}
+
+// DecodeRegistered reads from rd into to using the Codec registered for
+// protocolID and to's concrete type, so the req/resp dispatch doesn't need
+// to pass maxSize at the call site.
+func (r *Registry) DecodeRegistered(rd io.Reader, protocolID string, to interface{}) error {
+	codec, err := r.Codec(protocolID, to)
+	if err != nil {
+		return err
+	}
+	return codec.Encoding.DecodeWithMaxLength(rd, to, codec.MaxSize)
+}
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/prysmaticlabs/go-ssz"
+	"github.com/prysmaticlabs/prysm/beacon-chain/p2p/encoder/framing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -66,15 +66,7 @@ func (e SszNetworkEncoder) EncodeWithLength(w io.Writer, msg interface{}) (int,
 	if err != nil {
 		return 0, err
 	}
-	// write varint first
-	_, err = w.Write(proto.EncodeVarint(uint64(len(b))))
-	if err != nil {
-		return 0, err
-	}
-	if e.UseSnappyCompression {
-		return writeSnappyBuffer(w, b)
-	}
-	return w.Write(b)
+	return framing.NewDelimitedWriter(w).WriteMsg(b, e.snappyCompressor())
 }
 
 // EncodeWithMaxLength the proto message to the io.Writer. This encoding prefixes the byte slice with a protobuf varint
@@ -90,15 +82,18 @@ func (e SszNetworkEncoder) EncodeWithMaxLength(w io.Writer, msg interface{}, max
 	if uint64(len(b)) > maxSize {
 		return 0, fmt.Errorf("size of encoded message is %d which is larger than the provided max limit of %d", len(b), maxSize)
 	}
-	// write varint first
-	_, err = w.Write(proto.EncodeVarint(uint64(len(b))))
-	if err != nil {
-		return 0, err
+	return framing.NewDelimitedWriter(w).WriteMsg(b, e.snappyCompressor())
+}
+
+// snappyCompressor returns a framing.Compressor that wraps a writer with a
+// snappy buffered writer, or nil if this encoder isn't using compression.
+func (e SszNetworkEncoder) snappyCompressor() framing.Compressor {
+	if !e.UseSnappyCompression {
+		return nil
 	}
-	if e.UseSnappyCompression {
-		return writeSnappyBuffer(w, b)
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return snappy.NewBufferedWriter(w), nil
 	}
-	return w.Write(b)
 }
 
 func (e SszNetworkEncoder) doDecode(b []byte, to interface{}) error {
@@ -143,22 +138,18 @@ func (e SszNetworkEncoder) DecodeWithMaxLength(r io.Reader, to interface{}, maxS
 	if maxSize > MaxChunkSize {
 		return fmt.Errorf("maxSize %d exceeds max chunk size %d", maxSize, MaxChunkSize)
 	}
-	msgLen, err := readVarint(r)
-	if err != nil {
-		return err
-	}
+	var decompress framing.Decompressor
 	if e.UseSnappyCompression {
-		r = snappy.NewReader(r)
-	}
-	if msgLen > maxSize {
-		return fmt.Errorf("size of decoded message is %d which is larger than the provided max limit of %d", msgLen, maxSize)
+		decompress = func(r io.Reader) (io.Reader, error) {
+			return snappy.NewReader(r), nil
+		}
 	}
-	b := make([]byte, e.MaxLength(int(msgLen)))
-	numOfBytes, err := r.Read(b)
+	b, release, err := framing.NewDelimitedReader(r, maxSize).Next(decompress)
 	if err != nil {
 		return err
 	}
-	return e.doDecode(b[:numOfBytes], to)
+	defer release()
+	return e.doDecode(b, to)
 }
 
 // ProtocolSuffix returns the appropriate suffix for protocol IDs.
@@ -0,0 +1,111 @@
+package encoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type zstdTestMsg struct {
+	HeadSlot uint64
+}
+
+func TestSszZstdNetworkEncoder_EncodeDecodeWithMaxLength_RoundTrip(t *testing.T) {
+	e := SszZstdNetworkEncoder{}
+
+	want := &zstdTestMsg{HeadSlot: 42}
+	var buf bytes.Buffer
+	if _, err := e.EncodeWithMaxLength(&buf, want, MaxChunkSize); err != nil {
+		t.Fatalf("EncodeWithMaxLength: %v", err)
+	}
+
+	got := &zstdTestMsg{}
+	if err := e.DecodeWithMaxLength(&buf, got, MaxChunkSize); err != nil {
+		t.Fatalf("DecodeWithMaxLength: %v", err)
+	}
+	if got.HeadSlot != want.HeadSlot {
+		t.Fatalf("got HeadSlot %d, want %d", got.HeadSlot, want.HeadSlot)
+	}
+}
+
+func TestSszZstdNetworkEncoder_EncodeDecode_RoundTrip(t *testing.T) {
+	e := SszZstdNetworkEncoder{}
+
+	want := &zstdTestMsg{HeadSlot: 7}
+	var buf bytes.Buffer
+	if _, err := e.Encode(&buf, want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	got := &zstdTestMsg{}
+	if err := e.Decode(buf.Bytes(), got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.HeadSlot != want.HeadSlot {
+		t.Fatalf("got HeadSlot %d, want %d", got.HeadSlot, want.HeadSlot)
+	}
+}
+
+func TestSszZstdNetworkEncoder_DecodeWithMaxLength_RejectsOversizedFrame(t *testing.T) {
+	e := SszZstdNetworkEncoder{}
+
+	want := &zstdTestMsg{HeadSlot: 1}
+	var buf bytes.Buffer
+	if _, err := e.EncodeWithMaxLength(&buf, want, MaxChunkSize); err != nil {
+		t.Fatalf("EncodeWithMaxLength: %v", err)
+	}
+
+	got := &zstdTestMsg{}
+	if err := e.DecodeWithMaxLength(&buf, got, 1); err == nil {
+		t.Fatal("expected an error decoding a message larger than the provided max limit, got nil")
+	}
+}
+
+// zstdBomb compresses a large run of zero bytes, which zstd reduces to a
+// tiny compressed frame, to simulate a peer sending a decompression bomb.
+func zstdBomb(t *testing.T, decodedSize int) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter: %v", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(make([]byte, decodedSize), nil)
+}
+
+func TestSszZstdNetworkEncoder_Decode_RejectsZstdBomb(t *testing.T) {
+	e := SszZstdNetworkEncoder{}
+	bomb := zstdBomb(t, int(MaxChunkSize)*4)
+
+	if err := e.Decode(bomb, &zstdTestMsg{}); err == nil {
+		t.Fatal("expected an error decoding a zstd bomb, got nil")
+	}
+}
+
+func TestSszZstdNetworkEncoder_DecodeGossip_RejectsZstdBomb(t *testing.T) {
+	e := SszZstdNetworkEncoder{}
+	bomb := zstdBomb(t, int(MaxChunkSize)*4)
+
+	if err := e.DecodeGossip(bomb, &zstdTestMsg{}); err == nil {
+		t.Fatal("expected an error decoding a zstd bomb via gossip, got nil")
+	}
+}
+
+func TestSszZstdNetworkEncoder_CompressionLevel_Configurable(t *testing.T) {
+	fast := SszZstdNetworkEncoder{CompressionLevel: zstd.SpeedFastest}
+	best := SszZstdNetworkEncoder{CompressionLevel: zstd.SpeedBestCompression}
+
+	want := &zstdTestMsg{HeadSlot: 99}
+	var buf bytes.Buffer
+	if _, err := fast.Encode(&buf, want); err != nil {
+		t.Fatalf("Encode (fastest): %v", err)
+	}
+	got := &zstdTestMsg{}
+	if err := best.Decode(buf.Bytes(), got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.HeadSlot != want.HeadSlot {
+		t.Fatalf("got HeadSlot %d, want %d", got.HeadSlot, want.HeadSlot)
+	}
+}
@@ -0,0 +1,80 @@
+// Package chunked implements content-addressed, chunked compression for cold
+// BeaconState blobs. A state's SSZ serialization is split into fixed-size
+// chunks which are compressed independently and indexed by a small table of
+// contents (TOC) keyed on state root. Chunks that are byte-identical across
+// historical states (unchanged validator-registry pages, the historical_roots
+// tail, ...) are stored once and shared by digest, the same approach estargz
+// uses to give random access into an otherwise-monolithic compressed tar.
+package chunked
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/prysm/shared/hashutil"
+)
+
+// ChunkSize is the size, in bytes, that a state's uncompressed SSZ is split
+// into before each piece is compressed independently.
+const ChunkSize = 512 * 1024 // 512KiB
+
+// ErrUnknownTOC is returned by a Store implementation when no table of
+// contents has been saved for a given state root.
+var ErrUnknownTOC = errors.New("no chunk table of contents for state root")
+
+// ChunkDigest uniquely identifies a chunk's uncompressed contents.
+type ChunkDigest [32]byte
+
+// chunkDigest hashes an uncompressed chunk so identical chunks across states
+// dedupe to the same digest.
+func chunkDigest(b []byte) ChunkDigest {
+	return hashutil.Hash(b)
+}
+
+// TOCEntry describes a single chunk within a state's serialization.
+type TOCEntry struct {
+	// Digest identifies the chunk's uncompressed contents, shared across any
+	// other state whose chunk at any offset is byte-identical.
+	Digest ChunkDigest
+	// UncompressedOffset is this chunk's starting offset in the state's
+	// uncompressed SSZ serialization.
+	UncompressedOffset uint64
+	// CompressedLen is the number of compressed bytes occupied by this chunk.
+	CompressedLen uint64
+}
+
+// TOC is the ordered table of contents for one state root's chunks.
+type TOC []TOCEntry
+
+// Codec compresses and decompresses individual chunks. Callers supply either
+// a snappy or zstd implementation.
+type Codec interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+// Store persists chunk table-of-contents entries and the compressed chunk
+// bytes they reference. It is satisfied by a thin wrapper around the beacon
+// node's underlying key-value database.
+type Store interface {
+	// TOC returns the table of contents for root, or ErrUnknownTOC if none
+	// has been saved.
+	TOC(ctx context.Context, root [32]byte) (TOC, error)
+	// SaveTOC persists the table of contents for root.
+	SaveTOC(ctx context.Context, root [32]byte, toc TOC) error
+	// HasChunk reports whether a chunk with the given digest is already
+	// stored, and its compressed length if so, so writers can skip
+	// re-compressing and re-storing it without reading the chunk's bytes
+	// back just to learn its length.
+	HasChunk(ctx context.Context, digest ChunkDigest) (exists bool, compressedLen uint64, err error)
+	// SaveChunk persists compressed chunk bytes under digest.
+	SaveChunk(ctx context.Context, digest ChunkDigest, compressed []byte) error
+	// Chunk returns the compressed bytes previously saved under digest.
+	Chunk(ctx context.Context, digest ChunkDigest) ([]byte, error)
+	// SlotRoot returns the state root indexed under slot by a prior call to
+	// SaveSlotRoot, so a cold state can be looked up by slot without a
+	// separate store outside this package.
+	SlotRoot(ctx context.Context, slot uint64) ([32]byte, error)
+	// SaveSlotRoot indexes slot under root so SlotRoot can resolve it later.
+	SaveSlotRoot(ctx context.Context, slot uint64, root [32]byte) error
+}
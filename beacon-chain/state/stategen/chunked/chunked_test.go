@@ -0,0 +1,171 @@
+package chunked
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeStore is an in-memory Store used to exercise Write/Read without a real
+// database.
+type fakeStore struct {
+	tocs          map[[32]byte]TOC
+	slotRoots     map[uint64][32]byte
+	chunks        map[ChunkDigest][]byte
+	chunkReads    int
+	hasChunkCalls int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		tocs:      make(map[[32]byte]TOC),
+		slotRoots: make(map[uint64][32]byte),
+		chunks:    make(map[ChunkDigest][]byte),
+	}
+}
+
+func (s *fakeStore) TOC(ctx context.Context, root [32]byte) (TOC, error) {
+	toc, ok := s.tocs[root]
+	if !ok {
+		return nil, ErrUnknownTOC
+	}
+	return toc, nil
+}
+
+func (s *fakeStore) SaveTOC(ctx context.Context, root [32]byte, toc TOC) error {
+	s.tocs[root] = toc
+	return nil
+}
+
+func (s *fakeStore) HasChunk(ctx context.Context, digest ChunkDigest) (bool, uint64, error) {
+	s.hasChunkCalls++
+	chunk, ok := s.chunks[digest]
+	if !ok {
+		return false, 0, nil
+	}
+	return true, uint64(len(chunk)), nil
+}
+
+func (s *fakeStore) SaveChunk(ctx context.Context, digest ChunkDigest, compressed []byte) error {
+	s.chunks[digest] = compressed
+	return nil
+}
+
+func (s *fakeStore) Chunk(ctx context.Context, digest ChunkDigest) ([]byte, error) {
+	s.chunkReads++
+	chunk, ok := s.chunks[digest]
+	if !ok {
+		return nil, ErrUnknownTOC
+	}
+	return chunk, nil
+}
+
+func (s *fakeStore) SlotRoot(ctx context.Context, slot uint64) ([32]byte, error) {
+	root, ok := s.slotRoots[slot]
+	if !ok {
+		return [32]byte{}, ErrUnknownTOC
+	}
+	return root, nil
+}
+
+func (s *fakeStore) SaveSlotRoot(ctx context.Context, slot uint64, root [32]byte) error {
+	s.slotRoots[slot] = root
+	return nil
+}
+
+// identityCodec stores chunks uncompressed, so tests can assert on exact
+// bytes without pulling in a real compressor.
+type identityCodec struct{}
+
+func (identityCodec) Compress(b []byte) ([]byte, error)   { return append([]byte(nil), b...), nil }
+func (identityCodec) Decompress(b []byte) ([]byte, error) { return append([]byte(nil), b...), nil }
+
+func TestWriteRead_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	root := [32]byte{1}
+
+	raw := make([]byte, ChunkSize*2+100)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+
+	if err := Write(ctx, store, identityCodec{}, root, 7, raw); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := Read(ctx, store, identityCodec{}, root)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != len(raw) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(raw))
+	}
+	for i := range raw {
+		if got[i] != raw[i] {
+			t.Fatalf("byte %d: got %x, want %x", i, got[i], raw[i])
+		}
+	}
+
+	gotRoot, err := store.SlotRoot(ctx, 7)
+	if err != nil {
+		t.Fatalf("SlotRoot: %v", err)
+	}
+	if gotRoot != root {
+		t.Fatalf("got root %x, want %x", gotRoot, root)
+	}
+}
+
+func TestWrite_DedupsIdenticalChunksWithoutReadingThemBack(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+
+	// Two states sharing one unchanged chunk (a single zero-filled chunk).
+	raw := make([]byte, ChunkSize)
+	if err := Write(ctx, store, identityCodec{}, [32]byte{1}, 1, raw); err != nil {
+		t.Fatalf("Write #1: %v", err)
+	}
+	if len(store.chunks) != 1 {
+		t.Fatalf("got %d stored chunks after first write, want 1", len(store.chunks))
+	}
+
+	readsBefore := store.chunkReads
+	if err := Write(ctx, store, identityCodec{}, [32]byte{2}, 2, raw); err != nil {
+		t.Fatalf("Write #2: %v", err)
+	}
+	if len(store.chunks) != 1 {
+		t.Fatalf("got %d stored chunks after second (dedup) write, want still 1", len(store.chunks))
+	}
+	if store.chunkReads != readsBefore {
+		t.Fatalf("dedup write read the chunk back %d times, want 0", store.chunkReads-readsBefore)
+	}
+
+	toc, err := store.TOC(ctx, [32]byte{2})
+	if err != nil {
+		t.Fatalf("TOC: %v", err)
+	}
+	if len(toc) != 1 || toc[0].CompressedLen != ChunkSize {
+		t.Fatalf("got TOC %+v, want one entry with CompressedLen %d", toc, ChunkSize)
+	}
+}
+
+func TestPrefetchChunks_WarmsOnlyRequestedOffsets(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	root := [32]byte{3}
+
+	raw := make([]byte, ChunkSize*3)
+	for i := 0; i < len(raw); i += ChunkSize {
+		raw[i] = byte(i / ChunkSize) // make each chunk's content distinct
+	}
+	if err := Write(ctx, store, identityCodec{}, root, 1, raw); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	readsBefore := store.chunkReads
+	if err := PrefetchChunks(ctx, store, identityCodec{}, root, ChunkSize+1); err != nil {
+		t.Fatalf("PrefetchChunks: %v", err)
+	}
+	if got := store.chunkReads - readsBefore; got != 1 {
+		t.Fatalf("got %d chunk reads prefetching one offset, want 1", got)
+	}
+}
@@ -0,0 +1,79 @@
+package chunked
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// Read reconstructs a state's full uncompressed SSZ serialization from its
+// saved chunks, decompressing only the chunks referenced by root's TOC.
+func Read(ctx context.Context, store Store, codec Codec, root [32]byte) ([]byte, error) {
+	ctx, span := trace.StartSpan(ctx, "stategen.chunked.Read")
+	defer span.End()
+
+	toc, err := store.TOC(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	for _, e := range toc {
+		chunk, err := decompressChunk(ctx, store, codec, e.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decompress chunk %x", e.Digest)
+		}
+		raw = append(raw, chunk...)
+	}
+	return raw, nil
+}
+
+// PrefetchChunks fetches from store and decompresses the chunks needed to
+// reconstruct the bytes at the given uncompressed-SSZ field offsets of root's
+// state, without reconstructing the full state. The decompressed bytes are
+// not kept: a later Read fetches and decompresses each chunk again.
+func PrefetchChunks(ctx context.Context, store Store, codec Codec, root [32]byte, fieldOffsets ...uint64) error {
+	ctx, span := trace.StartSpan(ctx, "stategen.chunked.PrefetchChunks")
+	defer span.End()
+
+	toc, err := store.TOC(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	for _, offset := range fieldOffsets {
+		entry, ok := entryForOffset(toc, offset)
+		if !ok {
+			continue
+		}
+		if _, err := decompressChunk(ctx, store, codec, entry.Digest); err != nil {
+			return errors.Wrapf(err, "could not prefetch chunk %x", entry.Digest)
+		}
+	}
+	return nil
+}
+
+// entryForOffset returns the TOC entry covering the chunk containing the
+// given uncompressed-SSZ offset.
+func entryForOffset(toc TOC, offset uint64) (TOCEntry, bool) {
+	for i, e := range toc {
+		next := e.UncompressedOffset + ChunkSize
+		if i+1 < len(toc) {
+			next = toc[i+1].UncompressedOffset
+		}
+		if offset >= e.UncompressedOffset && offset < next {
+			return e, true
+		}
+	}
+	return TOCEntry{}, false
+}
+
+// decompressChunk fetches and decompresses the chunk stored under digest.
+func decompressChunk(ctx context.Context, store Store, codec Codec, digest ChunkDigest) ([]byte, error) {
+	compressed, err := store.Chunk(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decompress(compressed)
+}
@@ -0,0 +1,81 @@
+package chunked
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// Write splits raw (a state's uncompressed SSZ serialization) into ChunkSize
+// pieces, compresses each independently via codec, and saves a TOC for root
+// indexed by both root and slot. Chunks whose digest is already present in
+// store are not re-compressed or re-saved, so stretches of state that are
+// unchanged from a prior write cost near-zero additional disk.
+func Write(ctx context.Context, store Store, codec Codec, root [32]byte, slot uint64, raw []byte) error {
+	ctx, span := trace.StartSpan(ctx, "stategen.chunked.Write")
+	defer span.End()
+
+	// seenLens caches the compressed length of digests already resolved
+	// within this call, so a chunk repeated several times in the same state
+	// (e.g. a run of zero-valued padding) only costs one store lookup.
+	seenLens := make(map[ChunkDigest]uint64)
+
+	toc := make(TOC, 0, len(raw)/ChunkSize+1)
+	for offset := 0; offset < len(raw); offset += ChunkSize {
+		end := offset + ChunkSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		chunk := raw[offset:end]
+		digest := chunkDigest(chunk)
+
+		compressedLen, ok := seenLens[digest]
+		if !ok {
+			var err error
+			compressedLen, err = saveChunkIfNew(ctx, store, codec, digest, chunk)
+			if err != nil {
+				return err
+			}
+			seenLens[digest] = compressedLen
+		}
+
+		toc = append(toc, TOCEntry{
+			Digest:             digest,
+			UncompressedOffset: uint64(offset),
+			CompressedLen:      compressedLen,
+		})
+	}
+
+	if err := store.SaveTOC(ctx, root, toc); err != nil {
+		return errors.Wrap(err, "could not save chunk table of contents")
+	}
+	if err := store.SaveSlotRoot(ctx, slot, root); err != nil {
+		return errors.Wrap(err, "could not index chunk table of contents by slot")
+	}
+	return nil
+}
+
+// saveChunkIfNew compresses and saves chunk under digest if it isn't already
+// present in store, and returns its true compressed length either way. On a
+// dedup hit it trusts the length HasChunk reports rather than reading the
+// chunk's bytes back, so writing a state made up of long unchanged stretches
+// does no more I/O than the dedup check itself.
+func saveChunkIfNew(ctx context.Context, store Store, codec Codec, digest ChunkDigest, chunk []byte) (uint64, error) {
+	exists, compressedLen, err := store.HasChunk(ctx, digest)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not check for existing chunk")
+	}
+	if exists {
+		return compressedLen, nil
+	}
+
+	compressed, err := codec.Compress(chunk)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not compress chunk")
+	}
+	if err := store.SaveChunk(ctx, digest, compressed); err != nil {
+		return 0, errors.Wrap(err, "could not save chunk")
+	}
+	return uint64(len(compressed)), nil
+}
@@ -0,0 +1,110 @@
+package stategen
+
+import (
+	"context"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/go-ssz"
+	"github.com/prysmaticlabs/prysm/beacon-chain/state"
+	"github.com/prysmaticlabs/prysm/beacon-chain/state/stategen/chunked"
+	"go.opencensus.io/trace"
+)
+
+// snappyChunkCodec compresses individual cold-state chunks with snappy, the
+// same compression already used elsewhere on this path.
+type snappyChunkCodec struct{}
+
+func (snappyChunkCodec) Compress(b []byte) ([]byte, error) {
+	return snappy.Encode(nil /*dst*/, b), nil
+}
+
+func (snappyChunkCodec) Decompress(b []byte) ([]byte, error) {
+	return snappy.Decode(nil /*dst*/, b)
+}
+
+// chunkedColdStore returns s.beaconDB as a chunked.Store, if the underlying
+// database implementation supports chunked cold-state storage. DB
+// implementations that don't implement chunked.Store yet fall back to
+// reading and writing the whole serialized state, as before.
+func (s *State) chunkedColdStore() (chunked.Store, bool) {
+	store, ok := s.beaconDB.(chunked.Store)
+	return store, ok
+}
+
+// loadColdStateByRoot loads a cold state (a state at or below the archive
+// split point) for the given block root. When the DB supports chunked
+// storage, it is reconstructed from its saved chunks, decompressing only
+// what's needed.
+func (s *State) loadColdStateByRoot(ctx context.Context, blockRoot [32]byte) (*state.BeaconState, error) {
+	ctx, span := trace.StartSpan(ctx, "stateGen.loadColdStateByRoot")
+	defer span.End()
+
+	store, ok := s.chunkedColdStore()
+	if !ok {
+		return s.beaconDB.State(ctx, blockRoot)
+	}
+
+	raw, err := chunked.Read(ctx, store, snappyChunkCodec{}, blockRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read chunked cold state")
+	}
+	st := &state.BeaconState{}
+	if err := ssz.Unmarshal(raw, st); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal chunked cold state")
+	}
+	return st, nil
+}
+
+// loadColdStateBySlot loads a cold state for the given slot, resolving the
+// slot to its indexed block root before reading the chunked state.
+func (s *State) loadColdStateBySlot(ctx context.Context, slot uint64) (*state.BeaconState, error) {
+	ctx, span := trace.StartSpan(ctx, "stateGen.loadColdStateBySlot")
+	defer span.End()
+
+	store, ok := s.chunkedColdStore()
+	if !ok {
+		return s.beaconDB.StateBySlot(ctx, slot)
+	}
+
+	blockRoot, err := store.SlotRoot(ctx, slot)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not resolve cold state slot to root")
+	}
+	return s.loadColdStateByRoot(ctx, blockRoot)
+}
+
+// PrefetchChunks fetches and decompresses the chunks needed to reconstruct
+// the given uncompressed-SSZ field offsets of the cold state at blockRoot, via
+// chunked.PrefetchChunks; the decompressed bytes are not kept for later
+// reads. It is a no-op when the DB doesn't support chunked storage.
+func (s *State) PrefetchChunks(ctx context.Context, blockRoot [32]byte, fieldOffsets ...uint64) error {
+	ctx, span := trace.StartSpan(ctx, "stateGen.PrefetchChunks")
+	defer span.End()
+
+	store, ok := s.chunkedColdStore()
+	if !ok {
+		return nil
+	}
+	return chunked.PrefetchChunks(ctx, store, snappyChunkCodec{}, blockRoot, fieldOffsets...)
+}
+
+// saveColdState writes st's serialization through the chunked layer when the
+// DB supports it, so chunks unchanged since the last archived state are
+// stored once rather than duplicated, and falls back to saving the whole
+// state otherwise.
+func (s *State) saveColdState(ctx context.Context, blockRoot [32]byte, st *state.BeaconState) error {
+	ctx, span := trace.StartSpan(ctx, "stateGen.saveColdState")
+	defer span.End()
+
+	store, ok := s.chunkedColdStore()
+	if !ok {
+		return s.beaconDB.SaveState(ctx, st, blockRoot)
+	}
+
+	raw, err := ssz.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal cold state")
+	}
+	return chunked.Write(ctx, store, snappyChunkCodec{}, blockRoot, st.Slot(), raw)
+}